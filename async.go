@@ -0,0 +1,219 @@
+package malm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy controls what happens when the async queue set up by
+// SetAsync is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock makes the caller wait for room in the queue. This is the
+	// default: it never drops a message, but a slow sink can backpressure
+	// hot paths.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest queued message to make room.
+	OverflowDropOldest
+	// OverflowDropNewest discards the message that was about to be queued.
+	OverflowDropNewest
+)
+
+// LogStats reports counters about the async logging queue.
+type LogStats struct {
+	// Dropped is the number of messages discarded by the overflow policy
+	// since the package was initialized. SetAsync does not reset it.
+	Dropped uint64
+}
+
+var (
+	// overflowPolicy is stored as an atomic.Uint32 rather than guarded by a
+	// mutex since it's a single word read on every enqueue.
+	overflowPolicy atomic.Uint32
+	droppedCount   uint64
+
+	// asyncMu guards asyncCh/asyncDone and separates senders from the
+	// close that retires them: deliver/enqueue/Flush hold RLock for the
+	// duration of their send onto asyncCh, while SetAsync/Shutdown take the
+	// exclusive Lock before closing it, so a channel is never closed while
+	// a send to it may still be in flight.
+	asyncMu   sync.RWMutex
+	asyncCh   chan asyncMsg
+	asyncDone chan struct{}
+)
+
+// asyncMsg is either a log line to deliver to the registered sinks, or (when
+// flushDone is non-nil) a control message asking the async worker to
+// acknowledge that everything queued ahead of it has been delivered.
+type asyncMsg struct {
+	level     uint8
+	tag       string
+	line      string
+	t         time.Time
+	flushDone chan struct{}
+}
+
+// SetAsync switches malm to asynchronous delivery: log lines are queued on a
+// channel of size bufSize and written to the registered sinks by a single
+// background goroutine, which also flushes every sink every flushEvery (no
+// periodic flush if flushEvery is 0). Call Shutdown to disable async
+// delivery again and drain anything still queued.
+func SetAsync(bufSize int, flushEvery time.Duration) {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+
+	if asyncCh != nil {
+		close(asyncCh)
+		<-asyncDone
+	}
+
+	ch := make(chan asyncMsg, bufSize)
+	done := make(chan struct{})
+	asyncCh = ch
+	asyncDone = done
+
+	go asyncLoop(ch, done, flushEvery)
+}
+
+// SetOverflowPolicy sets what happens when the async queue is full.
+func SetOverflowPolicy(p OverflowPolicy) {
+	overflowPolicy.Store(uint32(p))
+}
+
+// currentOverflowPolicy returns the policy set by SetOverflowPolicy.
+func currentOverflowPolicy() OverflowPolicy {
+	return OverflowPolicy(overflowPolicy.Load())
+}
+
+// Stats returns the current async logging counters.
+func Stats() LogStats {
+	return LogStats{Dropped: atomic.LoadUint64(&droppedCount)}
+}
+
+// deliver hands a rendered log line to the registered sinks, either directly
+// or via the async queue if SetAsync has been called. The RLock is held
+// across the send into ch so that SetAsync/Shutdown can't close ch out from
+// under it.
+func deliver(level uint8, tag, line string, t time.Time) bool {
+	asyncMu.RLock()
+	defer asyncMu.RUnlock()
+
+	ch := asyncCh
+	if ch == nil {
+		return writeToSinks(level, tag, line, t)
+	}
+
+	return enqueue(ch, asyncMsg{level: level, tag: tag, line: line, t: t})
+}
+
+// enqueue adds m to ch, applying the configured OverflowPolicy if ch is full.
+func enqueue(ch chan asyncMsg, m asyncMsg) bool {
+	select {
+	case ch <- m:
+		return true
+	default:
+	}
+
+	switch currentOverflowPolicy() {
+	case OverflowDropNewest:
+		atomic.AddUint64(&droppedCount, 1)
+		return false
+
+	case OverflowDropOldest:
+		select {
+		case <-ch:
+			atomic.AddUint64(&droppedCount, 1)
+		default:
+		}
+		select {
+		case ch <- m:
+			return true
+		default:
+			atomic.AddUint64(&droppedCount, 1)
+			return false
+		}
+
+	default: // OverflowBlock
+		ch <- m
+		return true
+	}
+}
+
+// asyncLoop drains ch, writing each message to the registered sinks, until
+// ch is closed. It also flushes every sink every flushEvery, and acks flush
+// control messages once everything queued ahead of them has been written.
+func asyncLoop(ch chan asyncMsg, done chan struct{}, flushEvery time.Duration) {
+	defer close(done)
+
+	var tick <-chan time.Time
+	if flushEvery > 0 {
+		ticker := time.NewTicker(flushEvery)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			if m.flushDone != nil {
+				flushAllSinks()
+				close(m.flushDone)
+				continue
+			}
+			writeToSinks(m.level, m.tag, m.line, m.t)
+
+		case <-tick:
+			flushAllSinks()
+		}
+	}
+}
+
+// Flush blocks until every message queued so far has been written to the
+// registered sinks, then flushes the sinks themselves.
+func Flush() {
+	asyncMu.RLock()
+	ch := asyncCh
+	if ch == nil {
+		asyncMu.RUnlock()
+		flushAllSinks()
+		return
+	}
+
+	done := make(chan struct{})
+	ch <- asyncMsg{flushDone: done}
+	asyncMu.RUnlock()
+
+	<-done
+}
+
+// Shutdown disables async delivery, waiting for the queue to drain and every
+// sink to be closed, or for ctx to be done, whichever comes first. If async
+// delivery is not enabled, Shutdown returns nil at once and closes no sink.
+func Shutdown(ctx context.Context) error {
+	asyncMu.Lock()
+	ch := asyncCh
+	done := asyncDone
+	asyncCh = nil
+	asyncDone = nil
+	asyncMu.Unlock()
+
+	if ch == nil {
+		return nil
+	}
+
+	close(ch)
+
+	select {
+	case <-done:
+		closeAllSinks()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
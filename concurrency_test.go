@@ -0,0 +1,78 @@
+package malm
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentConfig exercises SetAsync, SetOverflowPolicy, SetOutputFormat
+// and SetDefaultWriter running concurrently with log calls that read the
+// state they configure. Run with -race; it catches the data races on
+// outputFormat, overflowPolicy, defaultSink and asyncCh that plain go vet
+// and go build don't.
+func TestConcurrentConfig(t *testing.T) {
+	TurnOnAllLogging()
+
+	t.Cleanup(func() {
+		_ = Shutdown(context.Background())
+		SetOutputFormat(FormatText)
+		SetOverflowPolicy(OverflowBlock)
+	})
+
+	stop := make(chan struct{})
+	var loggers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		loggers.Add(1)
+		go func() {
+			defer loggers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					Info("concurrent tick")
+				}
+			}
+		}()
+	}
+
+	var setters sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		i := i
+		setters.Add(4)
+
+		go func() {
+			defer setters.Done()
+			SetAsync(4, 0)
+		}()
+
+		go func() {
+			defer setters.Done()
+			if i%2 == 0 {
+				SetOverflowPolicy(OverflowDropNewest)
+			} else {
+				SetOverflowPolicy(OverflowBlock)
+			}
+		}()
+
+		go func() {
+			defer setters.Done()
+			if i%2 == 0 {
+				SetOutputFormat(FormatJSON)
+			} else {
+				SetOutputFormat(FormatText)
+			}
+		}()
+
+		go func() {
+			defer setters.Done()
+			SetDefaultWriter(io.Discard)
+		}()
+	}
+
+	setters.Wait()
+	close(stop)
+	loggers.Wait()
+}
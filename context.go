@@ -0,0 +1,150 @@
+package malm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ctxKey is the unexported context.Context key under which WithFields stores
+// a Logger, keeping it collision-free with keys from other packages.
+type ctxKey struct{}
+
+// Logger carries a set of key/value fields accumulated via WithFields and
+// includes them on every log call made through it, which is how malm
+// threads request-scoped metadata (request IDs, trace IDs, ...) across a
+// call chain without changing every function signature.
+type Logger struct {
+	fields []interface{}
+}
+
+// WithFields returns a copy of ctx carrying keysAndValues in addition to any
+// fields already attached to it by an earlier WithFields call. InfoCtx,
+// ErrorCtx and friends, as well as the Logger returned by FromContext(ctx),
+// include these fields on every subsequent log call.
+func WithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	existing := FromContext(ctx).fields
+
+	merged := make([]interface{}, 0, len(existing)+len(keysAndValues))
+	merged = append(merged, existing...)
+	merged = append(merged, keysAndValues...)
+
+	return context.WithValue(ctx, ctxKey{}, Logger{fields: merged})
+}
+
+// FromContext returns the Logger accumulated on ctx by WithFields, or a
+// Logger with no fields if ctx has none attached.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok {
+		return l
+	}
+	return Logger{}
+}
+
+// Info logs msg at Info level along with l's accumulated fields plus any
+// extra keysAndValues.
+func (l Logger) Info(msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logInfo, "INFO", msg, l.withFields(keysAndValues)...)
+}
+
+// Warn logs msg at Warn level along with l's accumulated fields plus any
+// extra keysAndValues.
+func (l Logger) Warn(msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logWarning, "WARN", msg, l.withFields(keysAndValues)...)
+}
+
+// Debug logs msg at Debug level along with l's accumulated fields plus any
+// extra keysAndValues.
+func (l Logger) Debug(msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logDebug, "DEBUG", msg, l.withFields(keysAndValues)...)
+}
+
+// Error logs msg and err at Error level along with l's accumulated fields
+// plus any extra keysAndValues.
+func (l Logger) Error(err error, msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logError, "ERROR", msg, withErr(err, l.withFields(keysAndValues))...)
+}
+
+// Fatal logs msg and err under the [FATAL] tag along with l's accumulated
+// fields plus any extra keysAndValues, then runs os.Exit(1).
+func (l Logger) Fatal(err error, msg string, keysAndValues ...interface{}) {
+	structuredDispatch(logFatal, "FATAL", msg, withErr(err, l.withFields(keysAndValues))...)
+	Flush()
+	os.Exit(1)
+}
+
+// withFields prepends l's accumulated fields to extra.
+func (l Logger) withFields(extra []interface{}) []interface{} {
+	if len(l.fields) == 0 {
+		return extra
+	}
+	return append(append([]interface{}{}, l.fields...), extra...)
+}
+
+// InfoCtx is the context-aware counterpart of Info: it logs the formatted
+// message along with any fields attached to ctx via WithFields.
+func InfoCtx(ctx context.Context, format string, args ...interface{}) bool {
+	return ctxDispatch(ctx, logInfo, "INFO", format, args...)
+}
+
+// WarnCtx is the context-aware counterpart of Warn.
+func WarnCtx(ctx context.Context, format string, args ...interface{}) bool {
+	return ctxDispatch(ctx, logWarning, "WARN", format, args...)
+}
+
+// DebugCtx is the context-aware counterpart of Debug.
+func DebugCtx(ctx context.Context, format string, args ...interface{}) bool {
+	return ctxDispatch(ctx, logDebug, "DEBUG", format, args...)
+}
+
+// ErrorCtx is the context-aware counterpart of Error.
+func ErrorCtx(ctx context.Context, format string, args ...interface{}) bool {
+	return ctxDispatch(ctx, logError, "ERROR", format, args...)
+}
+
+// FatalCtx is the context-aware counterpart of Fatal.
+func FatalCtx(ctx context.Context, format string, args ...interface{}) {
+	ctxDispatch(ctx, logFatal, "FATAL", format, args...)
+	Flush()
+	os.Exit(1)
+}
+
+// CustomCtx is the context-aware counterpart of Custom: it logs to writer
+// under logTag along with any fields attached to ctx via WithFields.
+func CustomCtx(ctx context.Context, writer io.Writer, logTag string, format string, args ...interface{}) bool {
+	if logCustom&currentLogBitmask() == 0 {
+		return false
+	}
+
+	r := logRecord{time: time.Now(), level: logTag, message: fmt.Sprintf(format, args...), kv: sanitizeKV(FromContext(ctx).fields)}
+	if r.verbose = logCustom&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = getDetails()
+	}
+
+	fmt.Fprintln(writer, renderRecord(r))
+	return true
+}
+
+// ctxDispatch formats the message, attaches ctx's fields, and fans the
+// result out to every Sink registered for numericalLogType.
+func ctxDispatch(ctx context.Context, numericalLogType uint8, logTag string, format string, args ...interface{}) bool {
+
+	// Checks if the message shouldn't be printed
+	if numericalLogType&currentLogBitmask() == 0 {
+		return false
+	}
+
+	// Checks if numericalLogType's severity is rate limited (see SetRateLimit)
+	if rateLimited(numericalLogType) {
+		return false
+	}
+
+	r := logRecord{time: time.Now(), level: logTag, message: fmt.Sprintf(format, args...), kv: sanitizeKV(FromContext(ctx).fields)}
+	if r.verbose = numericalLogType&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = getDetails()
+	}
+
+	return deliver(numericalLogType, logTag, renderRecord(r), r.time)
+}
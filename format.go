@@ -0,0 +1,185 @@
+package malm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// OutputFormat controls how log lines are rendered.
+type OutputFormat uint8
+
+const (
+	// FormatText renders lines the same way malm always has:
+	// "<date and time> [<tag>] <message>".
+	FormatText OutputFormat = iota
+	// FormatLogfmt renders lines as logfmt, e.g. ts=... level=info msg="...".
+	FormatLogfmt
+	// FormatJSON renders lines as a single JSON object per message.
+	FormatJSON
+)
+
+// outputFormat is stored as an atomic.Uint32 rather than guarded by a mutex
+// since it's a single word read on every log call.
+var outputFormat atomic.Uint32
+
+// SetOutputFormat changes how log lines are rendered: as plain text (the
+// default), logfmt, or JSON. It affects both the printf-style API
+// (Info, Error, ...) and the structured API (InfoS, ErrorS, ...).
+func SetOutputFormat(f OutputFormat) {
+	outputFormat.Store(uint32(f))
+}
+
+// currentOutputFormat returns the active OutputFormat set by SetOutputFormat.
+func currentOutputFormat() OutputFormat {
+	return OutputFormat(outputFormat.Load())
+}
+
+// logRecord holds everything needed to render a single log line in any of
+// the supported output formats.
+type logRecord struct {
+	time    time.Time
+	level   string
+	message string
+	verbose bool
+	file    string
+	caller  string
+	line    int
+	kv      []interface{} // flat, sanitized key/value pairs
+}
+
+// renderRecord formats r according to the currently configured OutputFormat.
+func renderRecord(r logRecord) string {
+	switch currentOutputFormat() {
+	case FormatJSON:
+		return renderJSON(r)
+	case FormatLogfmt:
+		return renderLogfmt(r)
+	default:
+		return renderText(r)
+	}
+}
+
+// renderText reproduces malm's original line format and appends any
+// structured key/value pairs as trailing "key=value" tokens.
+func renderText(r logRecord) string {
+	var b strings.Builder
+
+	b.WriteString(r.time.Format(currentTimeFormat()))
+	b.WriteString(" [")
+	b.WriteString(r.level)
+	b.WriteString("] ")
+
+	if r.verbose {
+		fmt.Fprintf(&b, "%s:%d:%s() ", r.file, r.line, r.caller)
+	}
+
+	b.WriteString(r.message)
+	appendKVText(&b, r.kv)
+
+	return b.String()
+}
+
+// renderLogfmt renders r as a single logfmt line.
+func renderLogfmt(r logRecord) string {
+	fields := []string{
+		"ts=" + r.time.Format(currentTimeFormat()),
+		"level=" + strings.ToLower(r.level),
+		"msg=" + logfmtValue(r.message),
+	}
+
+	if r.verbose {
+		fields = append(fields,
+			"file="+logfmtValue(r.file),
+			"func="+logfmtValue(r.caller),
+			fmt.Sprintf("line=%d", r.line),
+		)
+	}
+
+	for i := 0; i+1 < len(r.kv); i += 2 {
+		key := fmt.Sprint(r.kv[i])
+		fields = append(fields, key+"="+logfmtValue(valueToString(r.kv[i+1])))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// renderJSON renders r as a single JSON object, preserving field order.
+func renderJSON(r logRecord) string {
+	fields := []string{
+		jsonField("ts", r.time.Format(currentTimeFormat())),
+		jsonField("level", strings.ToLower(r.level)),
+		jsonField("msg", r.message),
+	}
+
+	if r.verbose {
+		fields = append(fields,
+			jsonField("file", r.file),
+			jsonField("func", r.caller),
+			jsonFieldInt("line", r.line),
+		)
+	}
+
+	for i := 0; i+1 < len(r.kv); i += 2 {
+		key := fmt.Sprint(r.kv[i])
+		fields = append(fields, jsonField(key, valueToString(r.kv[i+1])))
+	}
+
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+func jsonField(key, value string) string {
+	k, _ := json.Marshal(key)
+	v, _ := json.Marshal(value)
+	return string(k) + ":" + string(v)
+}
+
+func jsonFieldInt(key string, value int) string {
+	k, _ := json.Marshal(key)
+	return fmt.Sprintf("%s:%d", k, value)
+}
+
+// appendKVText appends kv to b as space-separated "key=value" tokens,
+// quoting values that contain whitespace.
+func appendKVText(b *strings.Builder, kv []interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		fmt.Fprintf(b, " %s=%s", key, logfmtValue(valueToString(kv[i+1])))
+	}
+}
+
+// logfmtValue quotes s if it contains whitespace or a double quote, which
+// keeps logfmt/text output parseable by tools that split on unquoted spaces.
+func logfmtValue(s string) string {
+	if strings.ContainsAny(s, " \t\r\n\"") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// valueToString renders v as a string, special-casing errors so they print
+// via Error() rather than Go's default %v representation.
+func valueToString(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+// sanitizeKV returns keysAndValues with an even length, appending a
+// synthetic "MALM_BAD_KEY" key paired with the dangling value if an odd
+// number of arguments was passed.
+func sanitizeKV(keysAndValues []interface{}) []interface{} {
+	if len(keysAndValues)%2 == 0 {
+		return keysAndValues
+	}
+
+	kv := make([]interface{}, 0, len(keysAndValues)+1)
+	kv = append(kv, keysAndValues[:len(keysAndValues)-1]...)
+	kv = append(kv, "MALM_BAD_KEY", keysAndValues[len(keysAndValues)-1])
+
+	return kv
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,7 +20,11 @@ const (
 	logCustom
 )
 
+// configMu guards logBitmask, verboseBitmask, defaultWriter and timeFormat,
+// which are otherwise read from formatter/dispatch on every log call and
+// written from Set* on any goroutine.
 var (
+	configMu       sync.RWMutex
 	logBitmask     uint8
 	verboseBitmask uint8
 	defaultWriter  io.Writer
@@ -29,38 +34,64 @@ var (
 // Initializes the package with default settings.
 func init() {
 
+	configMu.Lock()
 	defaultWriter = os.Stderr
 	timeFormat = "2006-01-02 15:04:05"
+	configMu.Unlock()
+
 	TurnOnAllLogging()
 	TurnOnAllVerbose()
+	setDefaultSink(os.Stderr)
+}
+
+// currentLogBitmask returns the active logBitmask.
+func currentLogBitmask() uint8 {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return logBitmask
+}
+
+// currentVerboseBitmask returns the active verboseBitmask.
+func currentVerboseBitmask() uint8 {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return verboseBitmask
+}
+
+// currentTimeFormat returns the active time layout used when rendering log lines.
+func currentTimeFormat() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return timeFormat
 }
 
 // Fatal is used for logging a fatal problem that has occured and will thus be using the [FATAL] tag.
 // Fatal works similarly to log.Fatal and is the only logging function that wont return a bool on completion.
 // Please note that Fatal will run os.Exit(1).
 func Fatal(format string, args ...interface{}) {
-	formatter(defaultWriter, logFatal, "FATAL", format, args...)
+	dispatch(logFatal, "FATAL", format, args...)
+	Flush()
 	os.Exit(1)
 }
 
 // Error is used for logging an error and will thus be using the [ERROR] tag.
 func Error(format string, args ...interface{}) bool {
-	return formatter(defaultWriter, logError, "ERROR", format, args...)
+	return dispatch(logError, "ERROR", format, args...)
 }
 
 // Warn is used for logging a warning and will thus be using the [WARN] tag.
 func Warn(format string, args ...interface{}) bool {
-	return formatter(defaultWriter, logWarning, "WARN", format, args...)
+	return dispatch(logWarning, "WARN", format, args...)
 }
 
 // Info is used for logging information and will thus be using the [INFO] tag.
 func Info(format string, args ...interface{}) bool {
-	return formatter(defaultWriter, logInfo, "INFO", format, args...)
+	return dispatch(logInfo, "INFO", format, args...)
 }
 
 // Debug is used for logging debug messages and will thus be using the [DEBUG] tag.
 func Debug(format string, args ...interface{}) bool {
-	return formatter(defaultWriter, logDebug, "DEBUG", format, args...)
+	return dispatch(logDebug, "DEBUG", format, args...)
 }
 
 // Custom is used for logging a customized messages under the tag of the users choice.
@@ -71,59 +102,150 @@ func Custom(writer io.Writer, logTag string, format string, args ...interface{})
 	return formatter(writer, logCustom, logTag, format, args...)
 }
 
-// formatter formats and crafts the log message.
-// It also makes sure if it is supposed to be printed.
+// formatter formats and crafts the log message, writing it directly to
+// writer. It is used by Custom, which lets the caller pick a one-off
+// destination instead of the registered sinks.
 // Returns true on success and false on block.
 func formatter(writer io.Writer, numericalLogType uint8, logTag string, format string, args ...interface{}) bool {
 
 	// Checks if the message shouldn't be printed
-	if numericalLogType&logBitmask == 0 {
+	if numericalLogType&currentLogBitmask() == 0 {
 		return false
 	}
 
-	now := time.Now().Format(timeFormat)
+	r := logRecord{time: time.Now(), level: logTag, message: fmt.Sprintf(format, args...)}
+	if r.verbose = numericalLogType&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = getDetails()
+	}
 
-	message := fmt.Sprintf(format, args...)
+	fmt.Fprintln(writer, renderRecord(r))
+	return true
+}
 
-	// Checks if the message shouldn't be verbose
-	if numericalLogType&verboseBitmask == 0 {
+// structuredFormatter is the structured-logging counterpart of formatter: it
+// renders msg together with keysAndValues instead of expanding a printf
+// format string, writing directly to writer. It is used by CustomS.
+func structuredFormatter(writer io.Writer, numericalLogType uint8, logTag string, msg string, keysAndValues ...interface{}) bool {
 
-		// <date and time> [<log tag>] <formatted message>\n
-		fmt.Fprintf(writer, "%s [%s] %s\n", now, logTag, message)
-		return true
+	// Checks if the message shouldn't be printed
+	if numericalLogType&currentLogBitmask() == 0 {
+		return false
 	}
 
-	// Message is verbose
-	fileName, caller, line := getDetails()
+	r := logRecord{time: time.Now(), level: logTag, message: msg, kv: sanitizeKV(keysAndValues)}
+	if r.verbose = numericalLogType&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = getDetails()
+	}
 
-	// <date and time> [<log tag>] <filePath>:<line number>:<caller>() <formatted message>\n
-	fmt.Fprintf(writer, "%s [%s] %s:%d:%s() %s\n", now, logTag, fileName, line, caller, message)
+	fmt.Fprintln(writer, renderRecord(r))
 	return true
 }
 
-// This function retrieves the function which called the function,
-// the file it is in and the line the function is on.
-func getDetails() (string, string, int) {
-	pc, path, line, ok := runtime.Caller(3)
+// dispatch formats the message and fans it out to every Sink registered for
+// numericalLogType (see AddSink), rather than writing to a single writer.
+// Returns true if at least one sink accepted the message or, when SetAsync
+// is active, if the message was queued.
+func dispatch(numericalLogType uint8, logTag string, format string, args ...interface{}) bool {
 
-	// Something went wrong
-	if !ok {
-		return "???", "???", -1
+	// Checks if the message shouldn't be printed
+	if numericalLogType&currentLogBitmask() == 0 {
+		return false
 	}
 
-	paths := strings.Split(path, "/")
-	file := paths[len(paths)-1]
+	// Checks if numericalLogType's severity is rate limited (see SetRateLimit)
+	if rateLimited(numericalLogType) {
+		return false
+	}
 
-	caller := runtime.FuncForPC(pc).Name()
-	stack := strings.Split(caller, ".")
-	caller = stack[len(stack)-1]
+	r := logRecord{time: time.Now(), level: logTag, message: fmt.Sprintf(format, args...)}
+	if r.verbose = numericalLogType&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = getDetails()
+	}
 
-	return file, caller, line
+	return deliver(numericalLogType, logTag, renderRecord(r), r.time)
+}
+
+// structuredDispatch is the structured-logging counterpart of dispatch. It is
+// used by InfoS, ErrorS and friends.
+func structuredDispatch(numericalLogType uint8, logTag string, msg string, keysAndValues ...interface{}) bool {
+
+	// Checks if the message shouldn't be printed
+	if numericalLogType&currentLogBitmask() == 0 {
+		return false
+	}
+
+	// Checks if numericalLogType's severity is rate limited (see SetRateLimit)
+	if rateLimited(numericalLogType) {
+		return false
+	}
+
+	r := logRecord{time: time.Now(), level: logTag, message: msg, kv: sanitizeKV(keysAndValues)}
+	if r.verbose = numericalLogType&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = getDetails()
+	}
+
+	return deliver(numericalLogType, logTag, renderRecord(r), r.time)
+}
+
+// packagePrefix is the import-path-qualified prefix of every function
+// defined in this package, e.g. "github.com/CarlFlo/malm.". getDetails uses
+// it to skip past malm's own frames (formatter, dispatch, Info, ...) and
+// report the first frame belonging to caller code instead.
+var packagePrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name() // e.g. "github.com/CarlFlo/malm.init.func1"
+
+	slash := strings.LastIndex(name, "/")
+	afterSlash := name[slash+1:] // "malm.init.func1"
+
+	dot := strings.Index(afterSlash, ".")
+	if dot < 0 {
+		return name
+	}
+
+	return name[:slash+1] + afterSlash[:dot+1]
+}()
+
+// getDetails walks up the call stack starting at its caller and returns the
+// file, function name and line of the first frame that isn't inside malm
+// itself. Walking instead of assuming a fixed depth means wrapping Info,
+// Debug and friends in helper functions still reports the caller's true
+// location.
+func getDetails() (string, string, int) {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:]) // skip runtime.Callers and getDetails itself
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.HasPrefix(frame.Function, packagePrefix) {
+			paths := strings.Split(frame.File, "/")
+			file := paths[len(paths)-1]
+
+			stack := strings.Split(frame.Function, ".")
+			caller := stack[len(stack)-1]
+
+			return file, caller, frame.Line
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return "???", "???", -1
 }
 
 // SetDefaultWriter allows for changing of the default io.Writer that the logger uses for outputting the message.
+// Internally this registers newWriter as a Sink on every level, alongside any
+// sinks added with AddSink.
 func SetDefaultWriter(newWriter io.Writer) {
+	configMu.Lock()
 	defaultWriter = newWriter
+	configMu.Unlock()
+
+	setDefaultSink(newWriter)
 }
 
 // SetLogBitmask allows for changing the permission of what types of log messages gets outputted.
@@ -132,6 +254,8 @@ func SetDefaultWriter(newWriter io.Writer) {
 //
 // The value '63' will turn on all logging
 func SetLogBitmask(bitmask uint8) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logBitmask = bitmask
 	return logBitmask
 }
@@ -142,6 +266,8 @@ func SetLogBitmask(bitmask uint8) uint8 {
 //
 // The value '63' will turn on all verbose logging
 func SetLogVerboseBitmask(bitmask uint8) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	verboseBitmask = bitmask
 	return verboseBitmask
 }
@@ -149,17 +275,23 @@ func SetLogVerboseBitmask(bitmask uint8) uint8 {
 // SetTimeFormat allows for changing how the time is printed when a message is logged.
 // Default: 2006-01-02 15:04:05
 func SetTimeFormat(format string) {
+	configMu.Lock()
+	defer configMu.Unlock()
 	timeFormat = format
 }
 
 // TurnOnAllLogging enables all types of logging messages to go though.
 func TurnOnAllLogging() uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logBitmask = logFatal | logError | logWarning | logInfo | logDebug | logCustom
 	return logBitmask
 }
 
 // TurnOnAllLogging enables verbosity for all types of logging messages.
 func TurnOnAllVerbose() uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	verboseBitmask = logFatal | logError | logWarning | logInfo | logDebug | logCustom
 	return verboseBitmask
 }
@@ -168,6 +300,8 @@ func TurnOnAllVerbose() uint8 {
 // Returns the bitmask after the change.
 // Please note that Fatal will run os.Exit(1) regardless of this setting.
 func SetLogFatal(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logFatal, &logBitmask)
 	return logBitmask
 }
@@ -175,6 +309,8 @@ func SetLogFatal(b bool) uint8 {
 // SetLogError sets if the Error log message will be printed.
 // Returns the bitmask after the change.
 func SetLogError(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logError, &logBitmask)
 	return logBitmask
 }
@@ -182,6 +318,8 @@ func SetLogError(b bool) uint8 {
 // SetLogWarning sets if the warning log message will be printed.
 // Returns the bitmask after the change.
 func SetLogWarning(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logWarning, &logBitmask)
 	return logBitmask
 }
@@ -189,6 +327,8 @@ func SetLogWarning(b bool) uint8 {
 // SetLogInfo sets if the information log message will be printed.
 // Returns the bitmask after the change.
 func SetLogInfo(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logInfo, &logBitmask)
 	return logBitmask
 }
@@ -196,6 +336,8 @@ func SetLogInfo(b bool) uint8 {
 // SetLogDebug sets if the debug log message will be printed.
 // Returns the bitmask after the change.
 func SetLogDebug(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logDebug, &logBitmask)
 	return logBitmask
 }
@@ -203,6 +345,8 @@ func SetLogDebug(b bool) uint8 {
 // SetLogCustom sets if custom log messages will be printed.
 // Returns the bitmask after the change.
 func SetLogCustom(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logCustom, &logBitmask)
 	return logBitmask
 }
@@ -212,6 +356,8 @@ func SetLogCustom(b bool) uint8 {
 // SetLogVerboseFatal sets if the Fatal log message should be verbose or not
 // Returns the bitmask after the change.
 func SetLogVerboseFatal(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logFatal, &verboseBitmask)
 	return verboseBitmask
 }
@@ -219,6 +365,8 @@ func SetLogVerboseFatal(b bool) uint8 {
 // SetLogVerboseError sets if the Error log message will be verbose or not
 // Returns the bitmask after the change.
 func SetLogVerboseError(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logError, &verboseBitmask)
 	return verboseBitmask
 }
@@ -226,6 +374,8 @@ func SetLogVerboseError(b bool) uint8 {
 // SetLogVerboseWarning sets if the warning log message will be verbose or not
 // Returns the bitmask after the change.
 func SetLogVerboseWarning(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logWarning, &verboseBitmask)
 	return verboseBitmask
 }
@@ -233,6 +383,8 @@ func SetLogVerboseWarning(b bool) uint8 {
 // SetLogVerboseInfo sets if the information log message will be verbose or not
 // Returns the bitmask after the change.
 func SetLogVerboseInfo(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logInfo, &verboseBitmask)
 	return verboseBitmask
 }
@@ -240,6 +392,8 @@ func SetLogVerboseInfo(b bool) uint8 {
 // SetLogVerboseDebug sets if the debug log message will be verbose or not
 // Returns the bitmask after the change.
 func SetLogVerboseDebug(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logDebug, &verboseBitmask)
 	return verboseBitmask
 }
@@ -247,11 +401,14 @@ func SetLogVerboseDebug(b bool) uint8 {
 // SetLogVerboseCustom sets if custom log messages will be verbose or not
 // Returns the bitmask after the change.
 func SetLogVerboseCustom(b bool) uint8 {
+	configMu.Lock()
+	defer configMu.Unlock()
 	logToggle(b, logCustom, &verboseBitmask)
 	return verboseBitmask
 }
 
 // Toggles the bit in the bitmask depending on if it should be on or off.
+// Callers must hold configMu.
 func logToggle(b bool, logType uint8, bitmask *uint8) {
 	if b {
 		*bitmask |= logType
@@ -0,0 +1,313 @@
+package malm
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is the maximum sustained rate of a token bucket, in events per
+// second. It mirrors the shape of golang.org/x/time/rate.Limit without
+// taking on the dependency, since malm has none.
+type Limit float64
+
+// bucket is a token bucket with a burst of 1: it allows an event through at
+// most once every 1/limit seconds, refilling continuously between calls.
+type bucket struct {
+	mu     sync.Mutex
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	return &bucket{limit: limit, tokens: 1, last: time.Now()}
+}
+
+func (b *bucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += float64(b.limit) * now.Sub(b.last).Seconds()
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+var (
+	rateLimitMu  sync.RWMutex
+	rateLimiters = map[uint8]*bucket{}
+)
+
+// SetRateLimit installs a token-bucket rate limiter for each level present
+// in perLevel, capping how many lines of that severity can be emitted per
+// second across every call site combined. Levels absent from perLevel are
+// left unthrottled. Passing nil (or an empty map) disables rate limiting
+// entirely.
+func SetRateLimit(perLevel map[uint8]Limit) {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+
+	rateLimiters = make(map[uint8]*bucket, len(perLevel))
+	for level, limit := range perLevel {
+		rateLimiters[level] = newBucket(limit)
+	}
+}
+
+// rateLimited reports whether a log line at level should be dropped because
+// its severity's token bucket, configured via SetRateLimit, is exhausted.
+func rateLimited(level uint8) bool {
+	rateLimitMu.RLock()
+	b := rateLimiters[level]
+	rateLimitMu.RUnlock()
+
+	return b != nil && !b.allow()
+}
+
+// siteState tracks the bookkeeping InfoEvery, InfoEveryT and their peers
+// need for a single call site: how many times it has been called, when it
+// last emitted, and how many consecutive identical messages are waiting to
+// be collapsed into a "[repeated N times]" summary.
+type siteState struct {
+	mu     sync.Mutex
+	level  uint8
+	tag    string
+	file   string
+	caller string
+	line   int
+
+	count    uint64
+	lastEmit time.Time
+	lastMsg  string
+	repeats  int
+	touched  time.Time
+}
+
+// siteTTL bounds how long a call site's bookkeeping survives without being
+// touched again, so a long-lived process doesn't accumulate one entry per
+// call site forever.
+const siteTTL = 10 * time.Minute
+
+var (
+	sitesMu     sync.Mutex
+	sites       = map[uintptr]*siteState{}
+	janitorOnce sync.Once
+)
+
+// siteFor returns the bookkeeping for the call site at pc, creating it if
+// this is the first call seen from that site.
+func siteFor(pc uintptr) *siteState {
+	sitesMu.Lock()
+	defer sitesMu.Unlock()
+
+	janitorOnce.Do(startSiteJanitor)
+
+	s, ok := sites[pc]
+	if !ok {
+		s = &siteState{}
+		sites[pc] = s
+	}
+	return s
+}
+
+// startSiteJanitor launches the background sweep that evicts call sites
+// that haven't been touched within siteTTL, flushing any pending "repeated
+// N times" summary first so a burst that simply stops isn't lost silently.
+func startSiteJanitor() {
+	go func() {
+		ticker := time.NewTicker(siteTTL / 2)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			sitesMu.Lock()
+			for pc, s := range sites {
+				s.mu.Lock()
+				stale := now.Sub(s.touched) > siteTTL
+				if stale && s.repeats > 0 {
+					emitLine(s.level, s.tag, fmt.Sprintf("%s [repeated %d times]", s.lastMsg, s.repeats), s.file, s.caller, s.line)
+				}
+				s.mu.Unlock()
+
+				if stale {
+					delete(sites, pc)
+				}
+			}
+			sitesMu.Unlock()
+		}
+	}()
+}
+
+// callerPC returns the program counter of the function that called the
+// InfoEvery/InfoEveryT-style wrapper two frames up, i.e. the real call site
+// malm should key its per-site throttling on.
+func callerPC() uintptr {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return 0
+	}
+	return pc
+}
+
+// pcDetails resolves pc (as captured by callerPC) to the same file/caller/
+// line shape getDetails() reports for the normal dispatch path, so verbose
+// output is consistent whether a line came from Info or InfoEvery.
+func pcDetails(pc uintptr) (file, caller string, line int) {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "???", "???", -1
+	}
+
+	file, line = fn.FileLine(pc)
+	paths := strings.Split(file, "/")
+	file = paths[len(paths)-1]
+
+	stack := strings.Split(fn.Name(), ".")
+	caller = stack[len(stack)-1]
+
+	return file, caller, line
+}
+
+// gate decides, for a given siteState, whether now is the time to let a
+// line through. It may update s's bookkeeping (a call counter or the
+// last-emit timestamp) as a side effect and is always called with s.mu held.
+type gate func(s *siteState, now time.Time) bool
+
+// countGate lets one call through for every n calls made from a site.
+func countGate(n int) gate {
+	if n < 1 {
+		n = 1
+	}
+	return func(s *siteState, _ time.Time) bool {
+		s.count++
+		return s.count%uint64(n) == 0
+	}
+}
+
+// timeGate lets at most one call through per d for a site.
+func timeGate(d time.Duration) gate {
+	return func(s *siteState, now time.Time) bool {
+		if !s.lastEmit.IsZero() && now.Sub(s.lastEmit) < d {
+			return false
+		}
+		s.lastEmit = now
+		return true
+	}
+}
+
+// everyDispatch implements the shared logic behind InfoEvery, InfoEveryT and
+// their peers: it decides, via g, whether this call should be emitted, and
+// collapses any run of byte-identical messages suppressed along the way
+// into a single "[repeated N times]" summary instead of dropping them
+// without a trace.
+func everyDispatch(pc uintptr, g gate, level uint8, tag, msg string) bool {
+	s := siteFor(pc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.touched = now
+	s.level, s.tag = level, tag
+	s.file, s.caller, s.line = pcDetails(pc)
+
+	repeat := msg == s.lastMsg
+	if !repeat && s.repeats > 0 {
+		emitLine(s.level, s.tag, fmt.Sprintf("%s [repeated %d times]", s.lastMsg, s.repeats), s.file, s.caller, s.line)
+		s.repeats = 0
+	}
+	s.lastMsg = msg
+
+	if !g(s, now) {
+		if repeat {
+			s.repeats++
+		}
+		return false
+	}
+
+	out := msg
+	if repeat && s.repeats > 0 {
+		out = fmt.Sprintf("%s [repeated %d times]", msg, s.repeats)
+	}
+	s.repeats = 0
+
+	return emitLine(level, tag, out, s.file, s.caller, s.line)
+}
+
+// emitLine renders msg as a log line for level/tag and fans it out to the
+// registered sinks, honoring both the log bitmask and SetRateLimit. file,
+// caller and line come from the call site InfoEvery/InfoEveryT resolved via
+// pcDetails, so verbose output matches what dispatch reports for Info,
+// Error and friends.
+func emitLine(level uint8, tag, msg, file, caller string, line int) bool {
+	if level&currentLogBitmask() == 0 {
+		return false
+	}
+	if rateLimited(level) {
+		return false
+	}
+
+	r := logRecord{time: time.Now(), level: tag, message: msg}
+	if r.verbose = level&currentVerboseBitmask() != 0; r.verbose {
+		r.file, r.caller, r.line = file, caller, line
+	}
+
+	return deliver(level, tag, renderRecord(r), r.time)
+}
+
+// InfoEvery logs the formatted message at Info level every nth call made
+// from this exact call site, which throttles a line inside a tight loop
+// without silencing it outright. Suppressed calls that repeat the previous
+// message are counted and reported as a "[repeated N times]" suffix on the
+// next line this site lets through; if the site logs a different message
+// first, or goes untouched for siteTTL, that count is emitted at once as a
+// summary line of its own.
+func InfoEvery(n int, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), countGate(n), logInfo, "INFO", fmt.Sprintf(format, args...))
+}
+
+// WarnEvery is the Warn counterpart of InfoEvery.
+func WarnEvery(n int, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), countGate(n), logWarning, "WARN", fmt.Sprintf(format, args...))
+}
+
+// ErrorEvery is the Error counterpart of InfoEvery.
+func ErrorEvery(n int, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), countGate(n), logError, "ERROR", fmt.Sprintf(format, args...))
+}
+
+// DebugEvery is the Debug counterpart of InfoEvery.
+func DebugEvery(n int, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), countGate(n), logDebug, "DEBUG", fmt.Sprintf(format, args...))
+}
+
+// InfoEveryT logs the formatted message at Info level at most once per d
+// for this exact call site. Like InfoEvery, a run of identical messages
+// suppressed in between is collapsed into a "[repeated N times]" summary.
+func InfoEveryT(d time.Duration, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), timeGate(d), logInfo, "INFO", fmt.Sprintf(format, args...))
+}
+
+// WarnEveryT is the Warn counterpart of InfoEveryT.
+func WarnEveryT(d time.Duration, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), timeGate(d), logWarning, "WARN", fmt.Sprintf(format, args...))
+}
+
+// ErrorEveryT is the Error counterpart of InfoEveryT.
+func ErrorEveryT(d time.Duration, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), timeGate(d), logError, "ERROR", fmt.Sprintf(format, args...))
+}
+
+// DebugEveryT is the Debug counterpart of InfoEveryT.
+func DebugEveryT(d time.Duration, format string, args ...interface{}) bool {
+	return everyDispatch(callerPC(), timeGate(d), logDebug, "DEBUG", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,188 @@
+package malm
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Sink receives fully-rendered log lines and is responsible for getting them
+// to their destination: a file, syslog, a network socket, and so on. Write is
+// called once per emitted log line; level is the bitmask value for the
+// line's severity (see logFatal, logError, ...) and t is the time the line
+// was produced.
+type Sink interface {
+	Write(level uint8, tag, msg string, t time.Time) error
+	Flush() error
+	Close() error
+}
+
+// allLevels is every severity bit malm defines, used to register a sink
+// against every level at once.
+const allLevels = logFatal | logError | logWarning | logInfo | logDebug | logCustom
+
+var (
+	sinksMu     sync.RWMutex
+	sinks       = map[uint8][]Sink{}
+	defaultSink Sink
+)
+
+// AddSink registers s to receive every log line whose level matches one of
+// the bits set in level, e.g. AddSink(logError|logWarning, s) sends both
+// errors and warnings to s. s is wrapped so that concurrent log calls never
+// interleave its Write calls, even if s itself isn't safe for concurrent use.
+func AddSink(level uint8, s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	addSinkLocked(level, s)
+}
+
+// addSinkLocked is AddSink's body; callers must hold sinksMu.
+func addSinkLocked(level uint8, s Sink) {
+	g := &guardedSink{s: s}
+
+	for lvl := uint8(1); lvl <= logCustom; lvl <<= 1 {
+		if level&lvl != 0 {
+			sinks[lvl] = append(sinks[lvl], g)
+		}
+	}
+}
+
+// RemoveSink unregisters s from every level matching the bits set in level.
+func RemoveSink(level uint8, s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	removeSinkLocked(level, s)
+}
+
+// removeSinkLocked is RemoveSink's body; callers must hold sinksMu.
+func removeSinkLocked(level uint8, s Sink) {
+	for lvl := uint8(1); lvl <= logCustom; lvl <<= 1 {
+		if level&lvl == 0 {
+			continue
+		}
+
+		list := sinks[lvl]
+		for i, existing := range list {
+			if g, ok := existing.(*guardedSink); ok && g.s == s {
+				sinks[lvl] = append(list[:i], list[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// sinksFor returns the sinks currently registered for level.
+func sinksFor(level uint8) []Sink {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+	return append([]Sink(nil), sinks[level]...)
+}
+
+// writeToSinks delivers line to every sink registered for level.
+// Returns true if at least one sink accepted it.
+func writeToSinks(level uint8, tag, line string, t time.Time) bool {
+	wrote := false
+	for _, s := range sinksFor(level) {
+		if err := s.Write(level, tag, line, t); err == nil {
+			wrote = true
+		}
+	}
+	return wrote
+}
+
+// uniqueSinks returns every distinct sink currently registered, regardless
+// of which levels it's attached to.
+func uniqueSinks() []Sink {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+
+	seen := map[Sink]bool{}
+	var unique []Sink
+
+	for _, list := range sinks {
+		for _, s := range list {
+			if !seen[s] {
+				seen[s] = true
+				unique = append(unique, s)
+			}
+		}
+	}
+
+	return unique
+}
+
+// flushAllSinks calls Flush on every distinct registered sink.
+func flushAllSinks() {
+	for _, s := range uniqueSinks() {
+		s.Flush()
+	}
+}
+
+// closeAllSinks calls Close on every distinct registered sink.
+func closeAllSinks() {
+	for _, s := range uniqueSinks() {
+		s.Close()
+	}
+}
+
+// setDefaultSink replaces the sink backing SetDefaultWriter, removing any
+// previously installed default sink first so repeated calls don't leak
+// entries into the registry. The read-then-write of defaultSink is done
+// under a single sinksMu critical section so concurrent SetDefaultWriter
+// calls can't race on it.
+func setDefaultSink(w io.Writer) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	if defaultSink != nil {
+		removeSinkLocked(allLevels, defaultSink)
+	}
+
+	defaultSink = writerSink{w: w}
+	addSinkLocked(allLevels, defaultSink)
+}
+
+// writerSink adapts a plain io.Writer into a Sink so that SetDefaultWriter
+// can participate in the same fan-out as any sink added with AddSink.
+type writerSink struct {
+	w io.Writer
+}
+
+func (s writerSink) Write(_ uint8, _, msg string, _ time.Time) error {
+	_, err := fmt.Fprintln(s.w, msg)
+	return err
+}
+
+func (writerSink) Flush() error { return nil }
+
+func (writerSink) Close() error { return nil }
+
+// guardedSink serializes access to an underlying Sink with a mutex, so that
+// two goroutines logging concurrently can never interleave partial writes
+// even if the Sink implementation itself isn't safe for concurrent use.
+// Every sink added via AddSink (including the one backing SetDefaultWriter)
+// is wrapped in one of these.
+type guardedSink struct {
+	mu sync.Mutex
+	s  Sink
+}
+
+func (g *guardedSink) Write(level uint8, tag, msg string, t time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.s.Write(level, tag, msg, t)
+}
+
+func (g *guardedSink) Flush() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.s.Flush()
+}
+
+func (g *guardedSink) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.s.Close()
+}
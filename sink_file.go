@@ -0,0 +1,156 @@
+package malm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RotatingFileSink is a Sink that writes log lines to a file on disk,
+// rotating to a new file once the current one exceeds maxSize bytes or has
+// been open longer than maxAge, and keeping at most maxBackups rotated
+// files. A zero maxSize or maxAge disables that rotation trigger.
+type RotatingFileSink struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	rotSeq int
+}
+
+// NewRotatingFileSink opens (creating if necessary) path for appending and
+// returns a RotatingFileSink backed by it.
+func NewRotatingFileSink(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFileSink, error) {
+	s := &RotatingFileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.openCurrent(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *RotatingFileSink) openCurrent() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = info.Size()
+	s.opened = time.Now()
+	return nil
+}
+
+// Write appends msg to the file, rotating first if a threshold has been
+// crossed.
+func (s *RotatingFileSink) Write(level uint8, tag, msg string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.needsRotation() {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := fmt.Fprintln(s.file, msg)
+	s.size += int64(n)
+	return err
+}
+
+func (s *RotatingFileSink) needsRotation() bool {
+	if s.maxSize > 0 && s.size >= s.maxSize {
+		return true
+	}
+	if s.maxAge > 0 && time.Since(s.opened) >= s.maxAge {
+		return true
+	}
+	return false
+}
+
+func (s *RotatingFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	// rotSeq disambiguates backups that would otherwise collide on the
+	// second-resolution timestamp alone, which a small maxSize and a busy
+	// logger can easily produce within the same process.
+	s.rotSeq++
+	backup := fmt.Sprintf("%s.%s.%04d", s.path, time.Now().Format("20060102-150405"), s.rotSeq)
+	if err := os.Rename(s.path, backup); err != nil {
+		return err
+	}
+
+	if err := s.pruneBackups(); err != nil {
+		return err
+	}
+
+	return s.openCurrent()
+}
+
+// pruneBackups deletes the oldest rotated files once there are more than
+// maxBackups of them. Backup names sort chronologically because they end in
+// a fixed-width timestamp followed by a fixed-width sequence number.
+func (s *RotatingFileSink) pruneBackups() error {
+	if s.maxBackups <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	if len(matches) <= s.maxBackups {
+		return nil
+	}
+
+	sort.Strings(matches)
+
+	for _, old := range matches[:len(matches)-s.maxBackups] {
+		if err := os.Remove(old); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush syncs the underlying file to disk.
+func (s *RotatingFileSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Sync()
+}
+
+// Close flushes and closes the underlying file.
+func (s *RotatingFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	syncErr := s.file.Sync()
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
@@ -0,0 +1,57 @@
+package malm
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+)
+
+// NetSink emits one JSON record per log line over a TCP or UDP connection.
+type NetSink struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewNetSink dials network ("tcp" or "udp") addr and returns a NetSink that
+// writes one JSON object per message to the connection.
+func NewNetSink(network, addr string) (*NetSink, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NetSink{conn: conn}, nil
+}
+
+// netSinkRecord is the wire format written by NetSink, one JSON object per
+// log line.
+type netSinkRecord struct {
+	Time  time.Time `json:"time"`
+	Level uint8     `json:"level"`
+	Tag   string    `json:"tag"`
+	Msg   string    `json:"msg"`
+}
+
+// Write marshals level, tag, msg and t as JSON and writes it to the
+// connection followed by a newline.
+func (s *NetSink) Write(level uint8, tag, msg string, t time.Time) error {
+	data, err := json.Marshal(netSinkRecord{Time: t, Level: level, Tag: tag, Msg: msg})
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.conn.Write(data)
+	return err
+}
+
+// Flush is a no-op: writes to conn are unbuffered.
+func (s *NetSink) Flush() error { return nil }
+
+// Close closes the underlying connection.
+func (s *NetSink) Close() error { return s.conn.Close() }
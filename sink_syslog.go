@@ -0,0 +1,51 @@
+//go:build !windows && !plan9 && !js
+
+package malm
+
+import (
+	"log/syslog"
+	"time"
+)
+
+// SyslogSink forwards log lines to a syslog daemon: the local one when
+// network is "", or a remote one over "udp" or "tcp" when network and addr
+// are set. Framing is whatever log/syslog.Dial produces (BSD-style,
+// RFC3164), not RFC5424.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink dials a syslog daemon. network is "" for the local syslog
+// socket, or "udp"/"tcp" to reach addr (e.g. "syslog.example.com:514").
+// tag identifies the program in the resulting log lines, and priority sets
+// the default facility/severity used before Write's level narrows it down.
+func NewSyslogSink(network, addr, tag string, priority syslog.Priority) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, addr, priority, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogSink{w: w}, nil
+}
+
+// Write sends msg to syslog at a severity derived from level.
+func (s *SyslogSink) Write(level uint8, tag, msg string, t time.Time) error {
+	switch level {
+	case logFatal:
+		return s.w.Crit(msg)
+	case logError:
+		return s.w.Err(msg)
+	case logWarning:
+		return s.w.Warning(msg)
+	case logDebug:
+		return s.w.Debug(msg)
+	default:
+		return s.w.Info(msg)
+	}
+}
+
+// Flush is a no-op: syslog.Writer has no buffering to flush.
+func (s *SyslogSink) Flush() error { return nil }
+
+// Close closes the underlying syslog connection.
+func (s *SyslogSink) Close() error { return s.w.Close() }
@@ -0,0 +1,48 @@
+package malm
+
+import (
+	"io"
+	"os"
+)
+
+// FatalS is the structured-logging counterpart of Fatal: it logs msg and err
+// together with keysAndValues under the [FATAL] tag and then runs os.Exit(1).
+func FatalS(err error, msg string, keysAndValues ...interface{}) {
+	structuredDispatch(logFatal, "FATAL", msg, withErr(err, keysAndValues)...)
+	Flush()
+	os.Exit(1)
+}
+
+// ErrorS is the structured-logging counterpart of Error: it logs msg and err
+// together with keysAndValues under the [ERROR] tag.
+func ErrorS(err error, msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logError, "ERROR", msg, withErr(err, keysAndValues)...)
+}
+
+// WarnS is the structured-logging counterpart of Warn.
+func WarnS(msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logWarning, "WARN", msg, keysAndValues...)
+}
+
+// InfoS is the structured-logging counterpart of Info.
+func InfoS(msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logInfo, "INFO", msg, keysAndValues...)
+}
+
+// DebugS is the structured-logging counterpart of Debug.
+func DebugS(msg string, keysAndValues ...interface{}) bool {
+	return structuredDispatch(logDebug, "DEBUG", msg, keysAndValues...)
+}
+
+// CustomS is the structured-logging counterpart of Custom.
+func CustomS(writer io.Writer, logTag string, msg string, keysAndValues ...interface{}) bool {
+	return structuredFormatter(writer, logCustom, logTag, msg, keysAndValues...)
+}
+
+// withErr prepends an "err" key/value pair derived from err, unless err is nil.
+func withErr(err error, keysAndValues []interface{}) []interface{} {
+	if err == nil {
+		return keysAndValues
+	}
+	return append([]interface{}{"err", err.Error()}, keysAndValues...)
+}
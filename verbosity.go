@@ -0,0 +1,172 @@
+package malm
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Verbose reports whether a given V level is currently enabled.
+// It is returned by V and its methods are no-ops when the level is disabled,
+// making it cheap to sprinkle V(n).Info(...) calls throughout hot paths.
+type Verbose bool
+
+// verbosityMu guards verbosityLevel and vmodule.
+var (
+	verbosityMu    sync.RWMutex
+	verbosityLevel int
+	vmodule        map[string]int
+)
+
+// SetVerbosity sets the package-level verbosity threshold used by V.
+// V(n) is enabled whenever n is less than or equal to this threshold.
+func SetVerbosity(level int) {
+	verbosityMu.Lock()
+	defer verbosityMu.Unlock()
+	verbosityLevel = level
+}
+
+// SetVModule overrides the verbosity threshold on a per-file or per-function
+// basis. The map is keyed by a pattern matched against the caller's file name
+// or function name (as reported by runtime.Caller), supporting a trailing
+// '*' wildcard, e.g. "auth/*" matches any file directly inside an auth
+// directory. A match in vmodule can only raise the threshold for the
+// matching caller: a level within the global threshold set by SetVerbosity
+// is enabled regardless of vmodule.
+func SetVModule(m map[string]int) {
+	verbosityMu.Lock()
+	defer verbosityMu.Unlock()
+	vmodule = m
+}
+
+// ParseVModule parses a vmodule string such as "server.go=3,auth/*=2" into
+// the map consumed by SetVModule and installs it as the active table.
+func ParseVModule(spec string) error {
+	m := make(map[string]int)
+
+	if strings.TrimSpace(spec) == "" {
+		SetVModule(m)
+		return nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("malm: invalid vmodule entry %q, expected pattern=level", pair)
+		}
+
+		level, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return err
+		}
+
+		m[strings.TrimSpace(parts[0])] = level
+	}
+
+	SetVModule(m)
+	return nil
+}
+
+// V reports whether verbosity level `level` is enabled, either because it is
+// within the global threshold set by SetVerbosity, or because vmodule
+// overrides it for the calling file or function. The fast path (level within
+// the global threshold) is a single comparison with no allocation and no
+// call to runtime.Caller; the vmodule lookup only runs when that fast path
+// fails.
+func V(level int) Verbose {
+	verbosityMu.RLock()
+	threshold := verbosityLevel
+	table := vmodule
+	verbosityMu.RUnlock()
+
+	if level <= threshold {
+		return Verbose(true)
+	}
+
+	if len(table) == 0 {
+		return Verbose(false)
+	}
+
+	file, caller, ok := vCallerInfo()
+	if !ok {
+		return Verbose(false)
+	}
+
+	for pattern, patternThreshold := range table {
+		if level > patternThreshold {
+			continue
+		}
+		if vmoduleMatch(pattern, file) || vmoduleMatch(pattern, caller) {
+			return Verbose(true)
+		}
+	}
+
+	return Verbose(false)
+}
+
+// vmoduleMatch reports whether pattern matches filePath, a path as reported
+// by runtime.Caller (or a bare function name). A pattern with no "/" is
+// matched against filePath's base name only, e.g. "server.go" matches
+// ".../internal/server.go". A pattern containing "/" is matched against
+// filePath's trailing path components instead, so "auth/*" matches any
+// file whose immediate parent directory is "auth" (e.g. ".../auth/jwt.go"),
+// rather than requiring the whole absolute path to match, which no pattern
+// in practice ever could.
+func vmoduleMatch(pattern, filePath string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, err := path.Match(pattern, filepath.Base(filePath))
+		return err == nil && matched
+	}
+
+	want := strings.Count(pattern, "/") + 1
+	segments := strings.Split(filepath.ToSlash(filePath), "/")
+	if len(segments) < want {
+		return false
+	}
+
+	suffix := strings.Join(segments[len(segments)-want:], "/")
+	matched, err := path.Match(pattern, suffix)
+	return err == nil && matched
+}
+
+// vCallerInfo returns the file path and function name of V's caller.
+func vCallerInfo() (file string, caller string, ok bool) {
+	pc, filePath, _, ok := runtime.Caller(2)
+	if !ok {
+		return "", "", false
+	}
+
+	fn := runtime.FuncForPC(pc).Name()
+	stack := strings.Split(fn, ".")
+
+	return filePath, stack[len(stack)-1], true
+}
+
+// Info logs at Info level if the verbosity level is enabled.
+func (v Verbose) Info(format string, args ...interface{}) bool {
+	if !v {
+		return false
+	}
+	return Info(format, args...)
+}
+
+// Debug logs at Debug level if the verbosity level is enabled.
+func (v Verbose) Debug(format string, args ...interface{}) bool {
+	if !v {
+		return false
+	}
+	return Debug(format, args...)
+}
+
+// Custom logs under a custom tag if the verbosity level is enabled.
+func (v Verbose) Custom(writer io.Writer, logTag string, format string, args ...interface{}) bool {
+	if !v {
+		return false
+	}
+	return Custom(writer, logTag, format, args...)
+}